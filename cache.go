@@ -0,0 +1,493 @@
+package decode
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fieldDescriptor is the precomputed metadata needed to set a single struct
+// field from a form value, so ParseToStruct never has to touch
+// reflect.Type.Field or parse a struct tag more than once per type and tag
+type fieldDescriptor struct {
+	index    []int
+	tag      string
+	setter   func(fieldV reflect.Value, value string) error
+	fileKind fileKind
+}
+
+// fileKind tags a descriptor built for a *multipart.FileHeader,
+// []*multipart.FileHeader or io.Reader field, none of which are set from a
+// string value and so need their own dispatch in parseFiles
+type fileKind int
+
+const (
+	notFile fileKind = iota
+	fileKindHeader
+	fileKindHeaders
+	fileKindReader
+)
+
+// fileFieldKind reports the fileKind for one of the three file-upload field
+// types, or notFile if t isn't one of them
+func fileFieldKind(t reflect.Type) fileKind {
+	switch t {
+	case fileHeaderType:
+		return fileKindHeader
+	case sliceOfFileHeaders:
+		return fileKindHeaders
+	case readerType:
+		return fileKindReader
+	}
+
+	return notFile
+}
+
+// descriptorKey scopes a cached descriptor to the (type, tag) pair it was
+// built for, since the same struct is commonly decoded with different tags
+// (e.g. "path" for route params and "form" for query params)
+type descriptorKey struct {
+	t   reflect.Type
+	tag string
+}
+
+var descriptorCache sync.Map // map[descriptorKey][]fieldDescriptor
+
+// getDescriptor returns the cached field descriptors for objT under
+// structTag, building and storing them on first sight of the pair
+func getDescriptor(objT reflect.Type, structTag string) []fieldDescriptor {
+	key := descriptorKey{t: objT, tag: structTag}
+
+	if cached, ok := descriptorCache.Load(key); ok {
+		return cached.([]fieldDescriptor)
+	}
+
+	descriptors := buildDescriptor(objT, structTag, nil, map[reflect.Type]bool{objT: true})
+	descriptorCache.Store(key, descriptors)
+
+	return descriptors
+}
+
+// isTimeType reports whether t is time.Time, the one struct type treated as
+// a scalar rather than something to recurse into
+func isTimeType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.String() == "time.Time"
+}
+
+// fieldTag returns the first token of fieldT's structTag value, and whether
+// the field should be considered at all
+func fieldTag(fieldT reflect.StructField, structTag string) (string, bool) {
+	tags := strings.Split(fieldT.Tag.Get(structTag), ",")
+	if len(tags) == 0 || len(tags[0]) == 0 || tags[0] == "-" {
+		return "", false
+	}
+	return tags[0], true
+}
+
+// buildDescriptor walks objT's fields, recursing into anonymous embedded
+// structs (and pointers to structs) so their fields are reachable under the
+// parent's tag namespace, into named struct fields under a dotted prefix
+// taken from their own tag, and resolves a setter for every other field
+// whose type is supported. seen holds every struct type on the current
+// recursion path, so a self- or mutually-referential struct (a tree node or
+// linked-list field pointing back to its own type) stops the walk instead of
+// recursing forever
+func buildDescriptor(objT reflect.Type, structTag string, parentIndex []int, seen map[reflect.Type]bool) []fieldDescriptor {
+	var descriptors []fieldDescriptor
+
+	for i := 0; i < objT.NumField(); i++ {
+		fieldT := objT.Field(i)
+		if fieldT.PkgPath != "" {
+			continue
+		}
+
+		index := append(append([]int{}, parentIndex...), i)
+		t := fieldT.Type
+
+		// file-upload fields never hold a string value, so they're matched
+		// by exact type ahead of the struct recursion below (a
+		// *multipart.FileHeader would otherwise look like a struct pointer
+		// to recurse into)
+		if fk := fileFieldKind(t); fk != notFile {
+			tag, ok := fieldTag(fieldT, structTag)
+			if !ok {
+				continue
+			}
+
+			descriptors = append(descriptors, fieldDescriptor{
+				index:    index,
+				tag:      tag,
+				fileKind: fk,
+			})
+			continue
+		}
+
+		// a registered converter always wins over the built-in struct
+		// recursion, so types such as uuid.UUID or decimal.Decimal can be
+		// bound directly instead of being walked field by field
+		if t.Kind() != reflect.Ptr && !hasConverter(t) {
+			if t.Kind() == reflect.Struct && !isTimeType(t) {
+				descriptors = append(descriptors, buildNestedDescriptor(fieldT, t, structTag, index, seen)...)
+				continue
+			}
+		}
+
+		if t.Kind() == reflect.Ptr && !hasConverter(t.Elem()) && t.Elem().Kind() == reflect.Struct && !isTimeType(t.Elem()) {
+			descriptors = append(descriptors, buildNestedDescriptor(fieldT, t.Elem(), structTag, index, seen)...)
+			continue
+		}
+
+		tag, ok := fieldTag(fieldT, structTag)
+		if !ok {
+			continue
+		}
+
+		if t.Kind() == reflect.Ptr {
+			elemSetter := setterFor(t.Elem())
+			if elemSetter == nil {
+				continue
+			}
+
+			descriptors = append(descriptors, fieldDescriptor{
+				index:  index,
+				tag:    tag,
+				setter: setPtr(t.Elem(), elemSetter),
+			})
+			continue
+		}
+
+		setter := setterFor(t)
+		if setter == nil {
+			continue
+		}
+
+		descriptors = append(descriptors, fieldDescriptor{
+			index:  index,
+			tag:    tag,
+			setter: setter,
+		})
+	}
+
+	return descriptors
+}
+
+// buildNestedDescriptor recurses into a struct-kind field. Anonymous fields
+// are flattened into the parent's tag namespace; named fields are flattened
+// under a dotted prefix taken from their own tag, e.g. an Address field
+// tagged `form:"address"` with a City field tagged `form:"city"` is reached
+// as "address.city". nestedT is skipped, rather than recursed into, if it's
+// already on the current path in seen
+func buildNestedDescriptor(fieldT reflect.StructField, nestedT reflect.Type, structTag string, index []int, seen map[reflect.Type]bool) []fieldDescriptor {
+	var prefix string
+
+	if !fieldT.Anonymous {
+		var ok bool
+		prefix, ok = fieldTag(fieldT, structTag)
+		if !ok {
+			return nil
+		}
+	}
+
+	if seen[nestedT] {
+		return nil
+	}
+
+	nested := buildDescriptor(nestedT, structTag, index, withSeen(seen, nestedT))
+
+	if fieldT.Anonymous {
+		return nested
+	}
+
+	for i := range nested {
+		nested[i].tag = prefix + "." + nested[i].tag
+	}
+
+	return nested
+}
+
+// withSeen returns a copy of seen with t added, so marking a type visited on
+// one branch of the recursion doesn't affect a sibling branch that legally
+// reuses the same struct type
+func withSeen(seen map[reflect.Type]bool, t reflect.Type) map[reflect.Type]bool {
+	next := make(map[reflect.Type]bool, len(seen)+1)
+	for k := range seen {
+		next[k] = true
+	}
+	next[t] = true
+
+	return next
+}
+
+// setterFor returns the value-setting function for t, or nil when t isn't a
+// supported field type. A type registered via RegisterConverter always takes
+// priority over the built-in kind-based setters
+func setterFor(t reflect.Type) func(reflect.Value, string) error {
+	if fn, ok := converterFor(t); ok {
+		return setConverted(fn)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return setBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return setInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return setUint
+	case reflect.Float32, reflect.Float64:
+		return setFloat
+	case reflect.Interface:
+		return setInterface
+	case reflect.String:
+		return setString
+	case reflect.Struct:
+		if t.String() == "time.Time" {
+			return setTime
+		}
+	case reflect.Slice:
+		switch t {
+		case sliceOfInts:
+			return setSliceOfInts
+		case sliceOfStrings:
+			return setSliceOfStrings
+		case sliceOfFloats:
+			return setSliceOfFloats
+		case sliceOfUints:
+			return setSliceOfUints
+		case sliceOfBools:
+			return setSliceOfBools
+		case sliceOfTimes:
+			return setSliceOfTimes
+		}
+	}
+
+	return nil
+}
+
+// setPtr wraps elemSetter so a nil pointer field is allocated before the
+// underlying value is set
+func setPtr(elemT reflect.Type, elemSetter func(reflect.Value, string) error) func(reflect.Value, string) error {
+	return func(fieldV reflect.Value, value string) error {
+		ptr := reflect.New(elemT)
+		if err := elemSetter(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		fieldV.Set(ptr)
+		return nil
+	}
+}
+
+// resolveField walks index from objV, allocating any nil pointer it finds
+// along the way so nested and pointer-to-struct fields can be set in place
+func resolveField(objV reflect.Value, index []int) reflect.Value {
+	v := objV
+
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(i)
+	}
+
+	return v
+}
+
+func parseBoolToken(value string) (result, ok bool) {
+	switch strings.ToLower(value) {
+	case "on", "1", "yes", "true":
+		return true, true
+	case "off", "0", "no", "false":
+		return false, true
+	}
+
+	return false, false
+}
+
+func setBool(fieldV reflect.Value, value string) error {
+	if b, ok := parseBoolToken(value); ok {
+		fieldV.SetBool(b)
+	}
+
+	return nil
+}
+
+func setInt(fieldV reflect.Value, value string) error {
+	x, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	fieldV.SetInt(x)
+	return nil
+}
+
+func setUint(fieldV reflect.Value, value string) error {
+	x, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	fieldV.SetUint(x)
+	return nil
+}
+
+func setFloat(fieldV reflect.Value, value string) error {
+	x, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+
+	fieldV.SetFloat(x)
+	return nil
+}
+
+func setInterface(fieldV reflect.Value, value string) error {
+	fieldV.Set(reflect.ValueOf(value))
+	return nil
+}
+
+func setString(fieldV reflect.Value, value string) error {
+	fieldV.SetString(value)
+	return nil
+}
+
+func setTime(fieldV reflect.Value, value string) error {
+	t, err := parseBuiltinTime(value)
+	// a value shorter than 8 characters matches no built-in layout and comes
+	// back as the zero time with no error, so registered layouts are tried
+	// for it too
+	if err != nil || len(value) < 8 {
+		if regT, regErr := parseRegisteredTime(value); regErr == nil {
+			t, err = regT, nil
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fieldV.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// parseBuiltinTime tries the layouts magic has always supported, picked by
+// the length (and, for date-times, the presence of a "T") of value
+func parseBuiltinTime(value string) (time.Time, error) {
+	var (
+		t   time.Time
+		err error
+	)
+
+	if len(value) >= 25 {
+		value = value[:25]
+		t, err = time.ParseInLocation(time.RFC3339, value, time.Local)
+	} else if len(value) >= 19 {
+		if strings.Contains(value, "T") {
+			value = value[:19]
+			t, err = time.ParseInLocation(formatDateTimeT, value, time.Local)
+		} else {
+			value = value[:19]
+			t, err = time.ParseInLocation(formatDateTime, value, time.Local)
+		}
+	} else if len(value) >= 10 {
+		if len(value) > 10 {
+			value = value[:10]
+		}
+		t, err = time.ParseInLocation(formatDate, value, time.Local)
+	} else if len(value) >= 8 {
+		if len(value) > 8 {
+			value = value[:8]
+		}
+		t, err = time.ParseInLocation(formatTime, value, time.Local)
+	}
+
+	return t, err
+}
+
+func setSliceOfInts(fieldV reflect.Value, value string) error {
+	formVals := strings.Split(value, ",")
+	fieldV.Set(reflect.MakeSlice(sliceOfInts, len(formVals), len(formVals)))
+
+	for i := 0; i < len(formVals); i++ {
+		val, err := strconv.Atoi(formVals[i])
+		if err != nil {
+			return err
+		}
+		fieldV.Index(i).SetInt(int64(val))
+	}
+
+	return nil
+}
+
+func setSliceOfStrings(fieldV reflect.Value, value string) error {
+	formVals := strings.Split(value, ",")
+	fieldV.Set(reflect.MakeSlice(sliceOfStrings, len(formVals), len(formVals)))
+
+	for i := 0; i < len(formVals); i++ {
+		fieldV.Index(i).SetString(formVals[i])
+	}
+
+	return nil
+}
+
+func setSliceOfFloats(fieldV reflect.Value, value string) error {
+	formVals := strings.Split(value, ",")
+	fieldV.Set(reflect.MakeSlice(sliceOfFloats, len(formVals), len(formVals)))
+
+	for i := 0; i < len(formVals); i++ {
+		val, err := strconv.ParseFloat(formVals[i], 64)
+		if err != nil {
+			return err
+		}
+		fieldV.Index(i).SetFloat(val)
+	}
+
+	return nil
+}
+
+func setSliceOfUints(fieldV reflect.Value, value string) error {
+	formVals := strings.Split(value, ",")
+	fieldV.Set(reflect.MakeSlice(sliceOfUints, len(formVals), len(formVals)))
+
+	for i := 0; i < len(formVals); i++ {
+		val, err := strconv.ParseUint(formVals[i], 10, 64)
+		if err != nil {
+			return err
+		}
+		fieldV.Index(i).SetUint(val)
+	}
+
+	return nil
+}
+
+func setSliceOfBools(fieldV reflect.Value, value string) error {
+	formVals := strings.Split(value, ",")
+	fieldV.Set(reflect.MakeSlice(sliceOfBools, len(formVals), len(formVals)))
+
+	for i := 0; i < len(formVals); i++ {
+		if b, ok := parseBoolToken(formVals[i]); ok {
+			fieldV.Index(i).SetBool(b)
+		}
+	}
+
+	return nil
+}
+
+func setSliceOfTimes(fieldV reflect.Value, value string) error {
+	formVals := strings.Split(value, ",")
+	fieldV.Set(reflect.MakeSlice(sliceOfTimes, len(formVals), len(formVals)))
+
+	for i := 0; i < len(formVals); i++ {
+		if err := setTime(fieldV.Index(i), formVals[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
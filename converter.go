@@ -0,0 +1,83 @@
+package decode
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	convertersMu sync.RWMutex
+	converters   = map[reflect.Type]func(string) (interface{}, error){}
+)
+
+// RegisterConverter plugs a custom string-to-value conversion for sample's
+// type into ParseToStruct, e.g. to decode a uuid.UUID or a custom enum
+// straight from a "path"/"form" tag without a pre-decode hook. It must be
+// called before the first ParseToStruct call for that type, since field
+// descriptors are cached on first use
+func RegisterConverter(sample interface{}, fn func(string) (interface{}, error)) {
+	t := reflect.TypeOf(sample)
+
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters[t] = fn
+}
+
+func converterFor(t reflect.Type) (func(string) (interface{}, error), bool) {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+
+	fn, ok := converters[t]
+	return fn, ok
+}
+
+func hasConverter(t reflect.Type) bool {
+	_, ok := converterFor(t)
+	return ok
+}
+
+func setConverted(fn func(string) (interface{}, error)) func(reflect.Value, string) error {
+	return func(fieldV reflect.Value, value string) error {
+		v, err := fn(value)
+		if err != nil {
+			return err
+		}
+
+		fieldV.Set(reflect.ValueOf(v))
+		return nil
+	}
+}
+
+var (
+	timeFormatsMu sync.RWMutex
+	timeFormats   []string
+)
+
+// RegisterTimeFormat adds an additional layout ParseToStruct will try when
+// decoding a time.Time field, on top of the built-in RFC3339/date/time
+// layouts
+func RegisterTimeFormat(layout string) {
+	timeFormatsMu.Lock()
+	defer timeFormatsMu.Unlock()
+	timeFormats = append(timeFormats, layout)
+}
+
+func registeredTimeFormats() []string {
+	timeFormatsMu.RLock()
+	defer timeFormatsMu.RUnlock()
+	return timeFormats
+}
+
+// parseRegisteredTime tries every layout added via RegisterTimeFormat,
+// returning the first one that parses value
+func parseRegisteredTime(value string) (time.Time, error) {
+	for _, layout := range registeredTimeFormats() {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errors.New("no registered time format matched")
+}
@@ -0,0 +1,80 @@
+package decode
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type hexColor struct {
+	R, G, B uint8
+}
+
+func parseHexColor(value string) (interface{}, error) {
+	var c hexColor
+	if _, err := fmt.Sscanf(value, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return nil, fmt.Errorf("invalid color %q: %w", value, err)
+	}
+	return c, nil
+}
+
+func TestRegisterConverter(t *testing.T) {
+	RegisterConverter(hexColor{}, parseHexColor)
+
+	type item struct {
+		Background hexColor  `form:"bg"`
+		Accent     *hexColor `form:"accent"`
+	}
+
+	container := &item{}
+	form := map[string]string{
+		"bg":     "#ff0000",
+		"accent": "#00ff00",
+	}
+	if err := ParseToStruct("form", form, container); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &item{
+		Background: hexColor{R: 0xff, G: 0x00, B: 0x00},
+		Accent:     &hexColor{R: 0x00, G: 0xff, B: 0x00},
+	}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
+
+func TestRegisterConverterError(t *testing.T) {
+	RegisterConverter(hexColor{}, parseHexColor)
+
+	type item struct {
+		Background hexColor `form:"bg"`
+	}
+
+	container := &item{}
+	form := map[string]string{"bg": "not-a-color"}
+	if err := ParseToStruct("form", form, container); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestRegisterTimeFormat(t *testing.T) {
+	RegisterTimeFormat("01/02/2006")
+
+	type item struct {
+		Birthday time.Time `form:"birthday"`
+	}
+
+	container := &item{}
+	form := map[string]string{"birthday": "07/29/2026"}
+	if err := ParseToStruct("form", form, container); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &item{Birthday: parseDate(t, "2026-07-29")}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
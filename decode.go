@@ -2,12 +2,14 @@ package decode
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"reflect"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -15,8 +17,11 @@ import (
 )
 
 const (
-	formTag = "form"
-	pathTag = "path"
+	formTag   = "form"
+	pathTag   = "path"
+	headerTag = "header"
+	cookieTag = "cookie"
+	fileTag   = "file"
 )
 
 // Decoder is an abstraction to decode info from a request into a container
@@ -57,6 +62,29 @@ func QueryParams(container interface{}, r *http.Request) error {
 	return ParseToStruct(formTag, values, container)
 }
 
+// Headers extract fields from the request headers, matching by canonical
+// header name (e.g. "Authorization", "X-Request-Id")
+func Headers(container interface{}, r *http.Request) error {
+	values := make(map[string]string)
+
+	for k := range r.Header {
+		values[http.CanonicalHeaderKey(k)] = r.Header.Get(k)
+	}
+
+	return ParseToStruct(headerTag, values, container)
+}
+
+// Cookies extract fields from the request cookies, matching by cookie name
+func Cookies(container interface{}, r *http.Request) error {
+	values := make(map[string]string)
+
+	for _, c := range r.Cookies() {
+		values[c.Name] = c.Value
+	}
+
+	return ParseToStruct(cookieTag, values, container)
+}
+
 // JSON unmarshal
 func JSON(container interface{}, r *http.Request) error {
 	if r.Body == nil {
@@ -66,6 +94,156 @@ func JSON(container interface{}, r *http.Request) error {
 	return json.NewDecoder(r.Body).Decode(container)
 }
 
+// XML unmarshal
+func XML(container interface{}, r *http.Request) error {
+	if r.Body == nil {
+		return errors.New("empty request body")
+	}
+
+	return xml.NewDecoder(r.Body).Decode(container)
+}
+
+// Form parses an application/x-www-form-urlencoded body and decodes it using
+// the "form" tag
+func Form(container interface{}, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	values := make(map[string]string)
+	for k := range r.PostForm {
+		values[k] = r.PostForm.Get(k)
+	}
+
+	return ParseToStruct(formTag, values, container)
+}
+
+// Body dispatches to JSON, XML or Form based on the request's Content-Type
+// header, returning an error for unrecognized content types
+func Body(container interface{}, r *http.Request) error {
+	contentType := r.Header.Get("Content-Type")
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	switch mediaType {
+	case "application/json":
+		return JSON(container, r)
+	case "application/xml", "text/xml":
+		return XML(container, r)
+	case "application/x-www-form-urlencoded":
+		return Form(container, r)
+	default:
+		return fmt.Errorf("unsupported content type: %s", contentType)
+	}
+}
+
+// Multipart parses a multipart/form-data request, buffering up to maxMemory
+// bytes in memory, and decodes scalar fields via the "form" tag and files via
+// the "file" tag into *multipart.FileHeader, []*multipart.FileHeader or
+// io.Reader fields, including ones reached by recursing into nested and
+// embedded structs, same as ParseToStruct. An io.Reader field is backed by
+// the file returned by FileHeader.Open (held in memory or spilled to disk)
+// and is closed automatically once r's context is done; for a request whose
+// context is never done, such as one built with http.NewRequest, the file
+// stays open
+func Multipart(maxMemory int64) Decoder {
+	return func(container interface{}, r *http.Request) error {
+		if err := r.ParseMultipartForm(maxMemory); err != nil {
+			return err
+		}
+
+		values := make(map[string]string)
+		for k, v := range r.MultipartForm.Value {
+			if len(v) > 0 {
+				values[k] = v[0]
+			}
+		}
+
+		if err := ParseToStruct(formTag, values, container); err != nil {
+			return err
+		}
+
+		return parseFiles(fileTag, r, container)
+	}
+}
+
+var (
+	fileHeaderType     = reflect.TypeOf((*multipart.FileHeader)(nil))
+	sliceOfFileHeaders = reflect.SliceOf(fileHeaderType)
+	readerType         = reflect.TypeOf((*io.Reader)(nil)).Elem()
+)
+
+// parseFiles sets struct fields tagged with structTag (including ones on
+// nested and embedded structs) from the files uploaded in r's parsed
+// multipart form. Any io.Reader field it opens is closed once r's context is
+// done, since the caller has no handle on the underlying file to close it
+// itself
+func parseFiles(structTag string, r *http.Request, container interface{}) error {
+	objT := reflect.TypeOf(container)
+	objV := reflect.ValueOf(container)
+	if container == nil || !isStructPtr(objT) {
+		return fmt.Errorf("%v must be  a struct pointer", container)
+	}
+
+	objT = objT.Elem()
+	objV = objV.Elem()
+
+	var closers []io.Closer
+
+	for _, d := range getDescriptor(objT, structTag) {
+		if d.fileKind == notFile {
+			continue
+		}
+
+		headers := r.MultipartForm.File[d.tag]
+		if len(headers) == 0 {
+			continue
+		}
+
+		fieldV := resolveField(objV, d.index)
+		if !fieldV.CanSet() {
+			continue
+		}
+
+		switch d.fileKind {
+		case fileKindHeader:
+			fieldV.Set(reflect.ValueOf(headers[0]))
+		case fileKindHeaders:
+			fieldV.Set(reflect.ValueOf(headers))
+		case fileKindReader:
+			f, err := headers[0].Open()
+			if err != nil {
+				return err
+			}
+
+			closers = append(closers, f)
+			fieldV.Set(reflect.ValueOf(io.Reader(f)))
+		}
+	}
+
+	if len(closers) > 0 {
+		closeOnDone(r, closers)
+	}
+
+	return nil
+}
+
+// closeOnDone closes every closer once r's context is cancelled, i.e. once
+// the handler serving r has returned, freeing the file descriptors opened
+// for its io.Reader fields without requiring the caller to do it
+func closeOnDone(r *http.Request, closers []io.Closer) {
+	go func() {
+		<-r.Context().Done()
+
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+}
+
 // Magic apply a series of decoders in the order they are
 // it is, decoder 1,2,3 will be applied in order 1,2,3
 // container must be a pointer to a struct
@@ -99,9 +277,15 @@ const (
 
 var sliceOfInts = reflect.TypeOf([]int(nil))
 var sliceOfStrings = reflect.TypeOf([]string(nil))
+var sliceOfFloats = reflect.TypeOf([]float64(nil))
+var sliceOfUints = reflect.TypeOf([]uint(nil))
+var sliceOfBools = reflect.TypeOf([]bool(nil))
+var sliceOfTimes = reflect.TypeOf([]time.Time(nil))
 
 // ParseToStruct converts a map of strings to its reference on a struct, it will
-// try to convert the data into the type defined in the struct field
+// try to convert the data into the type defined in the struct field. Field
+// descriptors are cached per struct type and tag, so only the first call for
+// a given combination pays the cost of walking reflect.Type
 func ParseToStruct(structTag string, form map[string]string, container interface{}) error {
 	if form == nil {
 		return nil
@@ -116,118 +300,26 @@ func ParseToStruct(structTag string, form map[string]string, container interface
 	objT = objT.Elem()
 	objV = objV.Elem()
 
-	for i := 0; i < objT.NumField(); i++ {
-		fieldV := objV.Field(i)
-		if !fieldV.CanSet() {
+	for _, d := range getDescriptor(objT, structTag) {
+		if d.fileKind != notFile {
 			continue
 		}
 
-		fieldT := objT.Field(i)
-		if fieldT.Anonymous && fieldT.Type.Kind() == reflect.Struct {
-			continue
-		}
-
-		tags := strings.Split(fieldT.Tag.Get(structTag), ",")
-		var tag string
-		if len(tags) == 0 || len(tags[0]) == 0 {
-			continue
-		} else if tags[0] == "-" {
+		value := form[d.tag]
+		if value == "" {
 			continue
-		} else {
-			tag = tags[0]
 		}
 
-		value := form[tag]
-		if value == "" {
+		fieldV := resolveField(objV, d.index)
+		if !fieldV.CanSet() {
 			continue
 		}
 
-		switch fieldT.Type.Kind() {
-		case reflect.Bool:
-			switch strings.ToLower(value) {
-			case "on", "1", "yes", "true":
-				fieldV.SetBool(true)
-				continue
-			case "off", "0", "no", "false":
-				fieldV.SetBool(false)
-				continue
-			}
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			x, err := strconv.ParseInt(value, 10, 64)
-			if err != nil {
-				return err
-			}
-			fieldV.SetInt(x)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			x, err := strconv.ParseUint(value, 10, 64)
-			if err != nil {
-				return err
-			}
-			fieldV.SetUint(x)
-		case reflect.Float32, reflect.Float64:
-			x, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return err
-			}
-			fieldV.SetFloat(x)
-		case reflect.Interface:
-			fieldV.Set(reflect.ValueOf(value))
-		case reflect.String:
-			fieldV.SetString(value)
-		case reflect.Struct:
-			switch fieldT.Type.String() {
-			case "time.Time":
-				var (
-					t   time.Time
-					err error
-				)
-				if len(value) >= 25 {
-					value = value[:25]
-					t, err = time.ParseInLocation(time.RFC3339, value, time.Local)
-				} else if len(value) >= 19 {
-					if strings.Contains(value, "T") {
-						value = value[:19]
-						t, err = time.ParseInLocation(formatDateTimeT, value, time.Local)
-					} else {
-						value = value[:19]
-						t, err = time.ParseInLocation(formatDateTime, value, time.Local)
-					}
-				} else if len(value) >= 10 {
-					if len(value) > 10 {
-						value = value[:10]
-					}
-					t, err = time.ParseInLocation(formatDate, value, time.Local)
-				} else if len(value) >= 8 {
-					if len(value) > 8 {
-						value = value[:8]
-					}
-					t, err = time.ParseInLocation(formatTime, value, time.Local)
-				}
-				if err != nil {
-					return err
-				}
-				fieldV.Set(reflect.ValueOf(t))
-			}
-		case reflect.Slice:
-			if fieldT.Type == sliceOfInts {
-				formVals := strings.Split(form[tag], ",")
-				fieldV.Set(reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(int(1))), len(formVals), len(formVals)))
-				for i := 0; i < len(formVals); i++ {
-					val, err := strconv.Atoi(formVals[i])
-					if err != nil {
-						return err
-					}
-					fieldV.Index(i).SetInt(int64(val))
-				}
-			} else if fieldT.Type == sliceOfStrings {
-				formVals := strings.Split(form[tag], ",")
-				fieldV.Set(reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf("")), len(formVals), len(formVals)))
-				for i := 0; i < len(formVals); i++ {
-					fieldV.Index(i).SetString(formVals[i])
-				}
-			}
+		if err := d.setter(fieldV, value); err != nil {
+			return err
 		}
 	}
+
 	return nil
 }
 
@@ -0,0 +1,610 @@
+package decode
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			t.Fatalf("write field: %s", err)
+		}
+	}
+
+	for k, content := range files {
+		fw, err := w.CreateFormFile(k, k+".txt")
+		if err != nil {
+			t.Fatalf("create form file: %s", err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatalf("write form file: %s", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("close writer: %s", err)
+	}
+
+	req := newRequest("POST", "/foo", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func newRequest(method, urlStr string, body io.Reader) *http.Request {
+	req, _ := http.NewRequest(method, urlStr, body)
+	return req
+}
+
+func TestHeaders(t *testing.T) {
+	type item struct {
+		Authorization string `header:"Authorization"`
+		TraceID       string `header:"X-Trace-Id"`
+	}
+
+	tc := []struct {
+		name      string
+		req       *http.Request
+		container interface{}
+		output    interface{}
+	}{
+		{
+			name:      "canonical header name",
+			req:       newRequest("GET", "/foo", nil),
+			container: &item{},
+			output:    &item{Authorization: "Bearer token"},
+		},
+		{
+			name:      "no headers",
+			req:       newRequest("GET", "/foo", nil),
+			container: &item{},
+			output:    &item{},
+		},
+	}
+
+	tc[0].req.Header.Set("Authorization", "Bearer token")
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			Headers(tt.container, tt.req)
+			if diff := cmp.Diff(tt.container, tt.output); diff != "" {
+				t.Errorf("%s: -got +want\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestCookies(t *testing.T) {
+	type item struct {
+		SessionID string `cookie:"session_id"`
+	}
+
+	req := newRequest("GET", "/foo", nil)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "abc123"})
+
+	container := &item{}
+	if err := Cookies(container, req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &item{SessionID: "abc123"}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
+
+func TestXML(t *testing.T) {
+	type item struct {
+		Name string `xml:"name"`
+	}
+
+	req := newRequest("POST", "/foo", bytes.NewBufferString(`<item><name>foo</name></item>`))
+	container := &item{}
+	if err := XML(container, req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &item{Name: "foo"}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
+
+func TestForm(t *testing.T) {
+	type item struct {
+		Name string `form:"name"`
+	}
+
+	req := newRequest("POST", "/foo", strings.NewReader("name=bob"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	container := &item{}
+	if err := Form(container, req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &item{Name: "bob"}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
+
+func TestBody(t *testing.T) {
+	type item struct {
+		Name string `json:"name" xml:"name" form:"name"`
+	}
+
+	tc := []struct {
+		name        string
+		contentType string
+		body        string
+		hasErr      bool
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"name": "foo"}`,
+		},
+		{
+			name:        "xml",
+			contentType: "application/xml",
+			body:        `<item><name>foo</name></item>`,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=foo",
+		},
+		{
+			name:        "unknown",
+			contentType: "application/protobuf",
+			body:        "",
+			hasErr:      true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest("POST", "/foo", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			container := &item{}
+			err := Body(container, req)
+			if (err == nil) == tt.hasErr {
+				t.Errorf("%s: expect err to be %t, got: %s", tt.name, tt.hasErr, err)
+			}
+
+			if !tt.hasErr {
+				if diff := cmp.Diff(container, &item{Name: "foo"}); diff != "" {
+					t.Errorf("%s: -got +want\n%s", tt.name, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestMultipart(t *testing.T) {
+	type item struct {
+		Name    string                  `form:"name"`
+		Avatar  *multipart.FileHeader   `file:"avatar"`
+		Docs    []*multipart.FileHeader `file:"docs"`
+		Receipt io.Reader               `file:"receipt"`
+	}
+
+	req := newMultipartRequest(t,
+		map[string]string{"name": "bob"},
+		map[string]string{"avatar": "avatar-bytes", "docs": "doc-bytes", "receipt": "receipt-bytes"},
+	)
+
+	container := &item{}
+	if err := Multipart(10 << 20)(container, req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if container.Name != "bob" {
+		t.Errorf("expected name to be bob, got: %s", container.Name)
+	}
+
+	if container.Avatar == nil || container.Avatar.Filename != "avatar.txt" {
+		t.Errorf("expected avatar file header, got: %v", container.Avatar)
+	}
+
+	if len(container.Docs) != 1 || container.Docs[0].Filename != "docs.txt" {
+		t.Errorf("expected docs file header, got: %v", container.Docs)
+	}
+
+	if container.Receipt == nil {
+		t.Fatal("expected receipt reader to be set")
+	}
+
+	content, err := ioutil.ReadAll(container.Receipt)
+	if err != nil {
+		t.Fatalf("read receipt: %s", err)
+	}
+	if string(content) != "receipt-bytes" {
+		t.Errorf("expected receipt content %q, got %q", "receipt-bytes", content)
+	}
+}
+
+func TestMultipartEmbedded(t *testing.T) {
+	type Attachments struct {
+		Avatar *multipart.FileHeader `file:"avatar"`
+	}
+
+	type item struct {
+		Attachments
+		Name string `form:"name"`
+	}
+
+	req := newMultipartRequest(t,
+		map[string]string{"name": "bob"},
+		map[string]string{"avatar": "avatar-bytes"},
+	)
+
+	container := &item{}
+	if err := Multipart(10 << 20)(container, req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if container.Avatar == nil || container.Avatar.Filename != "avatar.txt" {
+		t.Errorf("expected avatar file header, got: %v", container.Avatar)
+	}
+}
+
+func TestParseToStruct(t *testing.T) {
+	type item struct {
+		Name   string `form:"name"`
+		Number int
+		Money  float64
+	}
+
+	type itemComplete struct {
+		Name    string   `form:"name"`
+		Number  int      `form:"number"`
+		Money   float64  `form:"money"`
+		IsSafe  bool     `form:"issafe"`
+		Numbers []int    `form:"numbers"`
+		Friends []string `form:"friends"`
+	}
+
+	tc := []struct {
+		name      string
+		container interface{}
+		hasErr    bool
+		output    interface{}
+		form      map[string]string
+	}{
+		{
+			name:      "empty container",
+			container: nil,
+			hasErr:    true,
+			output:    nil,
+			form:      map[string]string{},
+		},
+		{
+			name:      "empty form",
+			container: nil,
+			hasErr:    false,
+			output:    nil,
+			form:      nil,
+		},
+		{
+			name:      "only string param",
+			container: &item{},
+			hasErr:    false,
+			output: &item{
+				Name: "foo",
+			},
+			form: map[string]string{"name": "foo"},
+		},
+		{
+			name:      "string and number, only name has tag",
+			container: &item{},
+			hasErr:    false,
+			output: &item{
+				Name: "foo",
+			},
+			form: map[string]string{
+				"name":   "foo",
+				"number": "2",
+			},
+		},
+		{
+			name:      "string and number",
+			container: &itemComplete{},
+			hasErr:    false,
+			output: &itemComplete{
+				Name:   "foo",
+				Number: 2,
+			},
+			form: map[string]string{
+				"name":   "foo",
+				"number": "2",
+			},
+		},
+		{
+			name:      "string, number and float",
+			container: &itemComplete{},
+			hasErr:    false,
+			output: &itemComplete{
+				Name:   "foo",
+				Number: 2,
+				Money:  12.30,
+			},
+			form: map[string]string{
+				"name":   "foo",
+				"number": "2",
+				"money":  "12.30",
+			},
+		},
+		{
+			name:      "string, number, float and bool",
+			container: &itemComplete{},
+			hasErr:    false,
+			output: &itemComplete{
+				Name:   "foo",
+				Number: 2,
+				Money:  12.30,
+				IsSafe: true,
+			},
+			form: map[string]string{
+				"name":   "foo",
+				"number": "2",
+				"money":  "12.30",
+				"issafe": "on",
+			},
+		},
+		{
+			name:      "bool is '1'",
+			container: &itemComplete{},
+			hasErr:    false,
+			output: &itemComplete{
+				IsSafe: true,
+			},
+			form: map[string]string{
+				"issafe": "1",
+			},
+		},
+		{
+			name:      "bool is 'true'",
+			container: &itemComplete{},
+			hasErr:    false,
+			output: &itemComplete{
+				IsSafe: true,
+			},
+			form: map[string]string{
+				"issafe": "true",
+			},
+		},
+		{
+			name:      "bool is 'yes'",
+			container: &itemComplete{},
+			hasErr:    false,
+			output: &itemComplete{
+				IsSafe: true,
+			},
+			form: map[string]string{
+				"issafe": "yes",
+			},
+		},
+		{
+			name:      "slice of ints",
+			container: &itemComplete{},
+			hasErr:    false,
+			output: &itemComplete{
+				Numbers: []int{1, 2, 3, 4},
+			},
+			form: map[string]string{
+				"numbers": "1,2,3,4",
+			},
+		},
+		{
+			name:      "slice of ints with trailing comma",
+			container: &itemComplete{},
+			hasErr:    true,
+			output: &itemComplete{
+				Numbers: []int{1, 2, 3, 0},
+			},
+			form: map[string]string{
+				"numbers": "1,2,3,",
+			},
+		},
+		{
+			name:      "slice of strings",
+			container: &itemComplete{},
+			hasErr:    false,
+			output: &itemComplete{
+				Friends: []string{"Bob", "Carl"},
+			},
+			form: map[string]string{
+				"friends": "Bob,Carl",
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParseToStruct("form", tt.form, tt.container)
+			if diff := cmp.Diff(tt.container, tt.output); diff != "" {
+				t.Errorf("%s: -got +want\n%s", tt.name, diff)
+			}
+			if (err == nil) == tt.hasErr {
+				t.Errorf("%s: expect err to be %t, got: %s", tt.name, tt.hasErr, err)
+			}
+		})
+	}
+}
+
+func TestParseToStructEmbedded(t *testing.T) {
+	type Base struct {
+		ID int `form:"id"`
+	}
+
+	type item struct {
+		Base
+		Name string `form:"name"`
+	}
+
+	container := &item{}
+	if err := ParseToStruct("form", map[string]string{"id": "2", "name": "foo"}, container); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &item{Base: Base{ID: 2}, Name: "foo"}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
+
+func TestParseToStructNested(t *testing.T) {
+	type Address struct {
+		City string `form:"city"`
+	}
+
+	type item struct {
+		Name    string  `form:"name"`
+		Address Address `form:"address"`
+	}
+
+	container := &item{}
+	form := map[string]string{"name": "foo", "address.city": "Berlin"}
+	if err := ParseToStruct("form", form, container); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &item{Name: "foo", Address: Address{City: "Berlin"}}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
+
+func TestParseToStructSelfReferential(t *testing.T) {
+	type category struct {
+		Name   string    `form:"name"`
+		Parent *category `form:"parent"`
+	}
+
+	container := &category{}
+	form := map[string]string{"name": "a"}
+	if err := ParseToStruct("form", form, container); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &category{Name: "a"}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
+
+func TestParseToStructPointer(t *testing.T) {
+	type Address struct {
+		City string `form:"city"`
+	}
+
+	type item struct {
+		Age     *int       `form:"age"`
+		Joined  *time.Time `form:"joined"`
+		Address *Address   `form:"address"`
+	}
+
+	container := &item{}
+	form := map[string]string{
+		"age":          "30",
+		"joined":       "2020-05-01",
+		"address.city": "Berlin",
+	}
+	if err := ParseToStruct("form", form, container); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if container.Age == nil || *container.Age != 30 {
+		t.Errorf("expected age to be 30, got: %v", container.Age)
+	}
+
+	wantJoined, _ := time.ParseInLocation("2006-01-02", "2020-05-01", time.Local)
+	if container.Joined == nil || !container.Joined.Equal(wantJoined) {
+		t.Errorf("expected joined to be %v, got: %v", wantJoined, container.Joined)
+	}
+
+	if container.Address == nil || container.Address.City != "Berlin" {
+		t.Errorf("expected address.city to be Berlin, got: %v", container.Address)
+	}
+}
+
+func TestParseToStructMoreSliceTypes(t *testing.T) {
+	type item struct {
+		Prices  []float64   `form:"prices"`
+		IDs     []uint      `form:"ids"`
+		Flags   []bool      `form:"flags"`
+		Renewed []time.Time `form:"renewed"`
+	}
+
+	container := &item{}
+	form := map[string]string{
+		"prices":  "1.5,2.25",
+		"ids":     "1,2,3",
+		"flags":   "true,false,yes",
+		"renewed": "2020-01-01,2021-02-02",
+	}
+	if err := ParseToStruct("form", form, container); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	output := &item{
+		Prices:  []float64{1.5, 2.25},
+		IDs:     []uint{1, 2, 3},
+		Flags:   []bool{true, false, true},
+		Renewed: []time.Time{parseDate(t, "2020-01-01"), parseDate(t, "2021-02-02")},
+	}
+	if diff := cmp.Diff(container, output); diff != "" {
+		t.Errorf("-got +want\n%s", diff)
+	}
+}
+
+func parseDate(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		t.Fatalf("parse date %q: %s", value, err)
+	}
+	return parsed
+}
+
+func BenchmarkParseToStruct(b *testing.B) {
+	type item struct {
+		Name    string  `form:"name"`
+		Number  int     `form:"number"`
+		Money   float64 `form:"money"`
+		IsSafe  bool    `form:"issafe"`
+		Numbers []int   `form:"numbers"`
+	}
+
+	form := map[string]string{
+		"name":    "foo",
+		"number":  "2",
+		"money":   "12.30",
+		"issafe":  "true",
+		"numbers": "1,2,3,4",
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var container item
+		if err := ParseToStruct("form", form, &container); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package decode
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Validator validates a container after every decoder ran against it
+type Validator interface {
+	Validate(container interface{}) error
+}
+
+// ValidationError carries a per-field message produced by a Validator
+type ValidationError struct {
+	Fields map[string]string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for field, msg := range e.Fields {
+		parts = append(parts, fmt.Sprintf("%s: %s", field, msg))
+	}
+
+	return fmt.Sprintf("validation failed: %s", strings.Join(parts, ", "))
+}
+
+// defaultValidator validates a container using "validate" struct tags
+type defaultValidator struct {
+	validate *validator.Validate
+}
+
+// NewValidator returns a Validator backed by go-playground/validator that
+// reads `validate:"..."` tags
+func NewValidator() Validator {
+	return &defaultValidator{validate: validator.New()}
+}
+
+// Validate implements Validator
+func (d *defaultValidator) Validate(container interface{}) error {
+	err := d.validate.Struct(container)
+	if err == nil {
+		return nil
+	}
+
+	fieldErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	fields := make(map[string]string, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		fields[fe.Field()] = fe.Tag()
+	}
+
+	return &ValidationError{Fields: fields}
+}
+
+// WithValidator returns a Decoder that runs v against the container, meant to
+// be passed as the last decoder to Magic so it validates the fully decoded
+// container
+//
+// Magic(&req, r,
+// 	decode.JSON,
+// 	decode.WithValidator(decode.NewValidator()),
+// )
+func WithValidator(v Validator) Decoder {
+	return func(container interface{}, r *http.Request) error {
+		return v.Validate(container)
+	}
+}
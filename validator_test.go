@@ -0,0 +1,46 @@
+package decode
+
+import (
+	"testing"
+)
+
+func TestWithValidator(t *testing.T) {
+	type item struct {
+		Name string `validate:"required"`
+		Age  int    `validate:"gte=18"`
+	}
+
+	tc := []struct {
+		name      string
+		container *item
+		hasErr    bool
+	}{
+		{
+			name:      "valid",
+			container: &item{Name: "bob", Age: 20},
+			hasErr:    false,
+		},
+		{
+			name:      "missing name and underage",
+			container: &item{Age: 10},
+			hasErr:    true,
+		},
+	}
+
+	decoder := WithValidator(NewValidator())
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decoder(tt.container, nil)
+			if (err == nil) == tt.hasErr {
+				t.Errorf("%s: expect err to be %t, got: %s", tt.name, tt.hasErr, err)
+			}
+
+			if tt.hasErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Errorf("%s: expected *ValidationError, got: %T", tt.name, err)
+				}
+			}
+		})
+	}
+}